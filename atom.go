@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// feed and entry mirror rss/channel/item but follow the Atom 1.0 schema
+// (RFC 4287) instead of RSS 2.0.
+type feed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+	Summary string     `xml:"summary"`
+	Content string     `xml:"content"`
+}
+
+func entryFromFeedItem(fi *feedItem) atomEntry {
+	return atomEntry{
+		ID:      fi.guid,
+		Title:   fi.title,
+		Updated: fi.date.Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "alternate", Href: fi.link}},
+		Summary: fi.description,
+		Content: fi.description,
+	}
+}
+
+func (s *sheet2rss) newAtomFeed(items []*feedItem) *feed {
+	entries := make([]atomEntry, 0, len(items))
+	newest := time.Time{}
+	for _, fi := range items {
+		entries = append(entries, entryFromFeedItem(fi))
+		if fi.date.After(newest) {
+			newest = fi.date
+		}
+	}
+
+	return &feed{
+		Title:   s.Title,
+		ID:      s.SiteURL,
+		Updated: newest.Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "alternate", Href: s.SiteURL},
+			// The self link must identify this Atom document itself
+			// (RFC 4287 §4.2.7.2), not the site's home page.
+			{Rel: "self", Href: s.mount + "/atom"},
+		},
+		Author:  atomPerson{Name: s.Title},
+		Entries: entries,
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// feedConfig describes one spreadsheet to serve as a feed. A config file
+// is a JSON object with a top-level "feeds" array of these.
+type feedConfig struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	SiteURL         string `json:"site_url"`
+	SpreadsheetKey  string `json:"spreadsheet_key"`
+	RefreshInterval string `json:"refresh_interval,omitempty"`
+	NumEntries      int    `json:"num_entries,omitempty"`
+
+	// Mount is the base path the feed is served under, e.g. "/feeds/foo".
+	// It defaults to "/feeds/<id>".
+	Mount string `json:"mount,omitempty"`
+
+	// Webhook and SMTP, if set, are wired up as the feed's ItemHandler so
+	// new items trigger a notification.
+	Webhook *webhookConfig `json:"webhook,omitempty"`
+	SMTP    *smtpConfig    `json:"smtp,omitempty"`
+}
+
+type webhookConfig struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type smtpConfig struct {
+	Addr     string   `json:"addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+func (fc feedConfig) mount() string {
+	if fc.Mount != "" {
+		return fc.Mount
+	}
+	return "/feeds/" + fc.ID
+}
+
+type feedsConfig struct {
+	Feeds []feedConfig `json:"feeds"`
+}
+
+func loadConfig(path string) (*feedsConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg feedsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	ids := make(map[string]bool, len(cfg.Feeds))
+	mounts := make(map[string]bool, len(cfg.Feeds))
+	for _, fc := range cfg.Feeds {
+		if fc.ID == "" {
+			return nil, fmt.Errorf("parsing %s: feed has an empty id", path)
+		}
+		if ids[fc.ID] {
+			return nil, fmt.Errorf("parsing %s: duplicate feed id %q", path, fc.ID)
+		}
+		ids[fc.ID] = true
+
+		mount := fc.mount()
+		if mounts[mount] {
+			return nil, fmt.Errorf("parsing %s: feed %q: duplicate mount %q", path, fc.ID, mount)
+		}
+		mounts[mount] = true
+	}
+
+	return &cfg, nil
+}
+
+func newSheet2RSS(fc feedConfig) *sheet2rss {
+	s := &sheet2rss{
+		Title:           fc.Title,
+		Desc:            fc.Description,
+		SiteURL:         fc.SiteURL,
+		mount:           fc.mount(),
+		url:             fmt.Sprintf(sourceFmt, fc.SpreadsheetKey),
+		numEntries:      fc.NumEntries,
+		refreshInterval: parseRefreshInterval(fc.ID, fc.RefreshInterval),
+		mu:              new(sync.Mutex),
+	}
+
+	var notifiers []func(old, new []*feedItem)
+	if fc.Webhook != nil {
+		notifiers = append(notifiers, (&WebhookNotifier{URL: fc.Webhook.URL, Secret: fc.Webhook.Secret}).Notify)
+	}
+	if fc.SMTP != nil {
+		var auth smtp.Auth
+		if fc.SMTP.Username != "" {
+			auth = smtp.PlainAuth("", fc.SMTP.Username, fc.SMTP.Password, strings.Split(fc.SMTP.Addr, ":")[0])
+		}
+		notifiers = append(notifiers, (&SMTPNotifier{Addr: fc.SMTP.Addr, From: fc.SMTP.From, To: fc.SMTP.To, Auth: auth}).Notify)
+	}
+	if len(notifiers) > 0 {
+		s.ItemHandler = chainNotifiers(notifiers...)
+	}
+
+	return s
+}
+
+// indexEntry is what the "/" handler lists for each mounted feed.
+type indexEntry struct {
+	Title string
+	Mount string
+}
+
+func registerFeed(mux *http.ServeMux, fc feedConfig, handler *sheet2rss) indexEntry {
+	mount := fc.mount()
+	mux.Handle(mount, handler)
+	mux.Handle(mount+"/atom", handler)
+	mux.HandleFunc(mount+"/feed.json", handler.jsonFeedHandler)
+	mux.HandleFunc(mount+"/healthz", handler.healthz)
+	return indexEntry{Title: handler.Title, Mount: mount}
+}
+
+// rootHealthzHandler aggregates every feed's health under a single
+// root /healthz, so a process supervisor / k8s probe has one path to
+// watch regardless of how many feeds -config describes.
+func rootHealthzHandler(feeds map[string]*sheet2rss) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ready := true
+		byID := make(map[string]healthzResponse, len(feeds))
+		for id, s := range feeds {
+			resp := s.health()
+			byID[id] = resp
+			if !resp.Ready {
+				ready = false
+			}
+		}
+		writeHealthz(w, ready, byID)
+	}
+}
+
+func indexHandler(feeds []indexEntry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<!DOCTYPE html><html><head><title>Feeds</title></head><body><ul>")
+		for _, f := range feeds {
+			fmt.Fprintf(w, `<li><a href="%s">%s</a> (<a href="%s/atom">atom</a>, <a href="%s/feed.json">json</a>)</li>`,
+				html.EscapeString(f.Mount), html.EscapeString(f.Title), html.EscapeString(f.Mount), html.EscapeString(f.Mount))
+		}
+		fmt.Fprint(w, "</ul></body></html>")
+	}
+}
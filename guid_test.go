@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeGUIDStableAcrossReexport(t *testing.T) {
+	headers := []string{"description", "article", "activity", "branch", "detail"}
+	row := []string{"Signed a bill", "http://example.com/1", "signed", "executive", "Some detail"}
+
+	var fi1, fi2 feedItem
+	fi1.fromRecord(headers, row)
+	fi2.fromRecord(headers, row)
+
+	if fi1.guid == "" {
+		t.Fatal("guid is empty")
+	}
+	if fi1.guid != fi2.guid {
+		t.Errorf("guid changed across identical rows: %q != %q", fi1.guid, fi2.guid)
+	}
+}
+
+func TestComputeGUIDIgnoresMissingDateColumn(t *testing.T) {
+	headers := []string{"description", "article", "activity", "branch", "detail"}
+	row := []string{"Signed a bill", "http://example.com/1", "signed", "executive", "Some detail"}
+
+	var a, b feedItem
+	a.fromRecord(headers, row)
+	time.Sleep(2 * time.Millisecond) // let a synthesized time.Now() drift
+	b.fromRecord(headers, row)
+
+	if a.guid != b.guid {
+		t.Errorf("guid churned when the date column was absent: %q != %q", a.guid, b.guid)
+	}
+}
+
+func TestComputeGUIDChangesWithContent(t *testing.T) {
+	headers := []string{"description", "article", "activity", "branch", "detail"}
+
+	var a, b feedItem
+	a.fromRecord(headers, []string{"Signed a bill", "http://example.com/1", "signed", "executive", "Some detail"})
+	b.fromRecord(headers, []string{"Vetoed a bill", "http://example.com/1", "signed", "executive", "Some detail"})
+
+	if a.guid == b.guid {
+		t.Error("guid did not change when row content changed")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	etag := `"abc123"`
+
+	cases := []struct {
+		name string
+		req  func() *http.Request
+		want bool
+	}{
+		{
+			name: "matching etag",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("If-None-Match", etag)
+				return r
+			},
+			want: true,
+		},
+		{
+			name: "mismatched etag",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("If-None-Match", `"other"`)
+				return r
+			},
+			want: false,
+		},
+		{
+			name: "if-modified-since at or after last modified",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+				return r
+			},
+			want: true,
+		},
+		{
+			name: "if-modified-since before last modified",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("If-Modified-Since", lastModified.Add(-time.Hour).Format(http.TimeFormat))
+				return r
+			},
+			want: false,
+		},
+		{
+			name: "no validators",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodGet, "/", nil)
+				return r
+			},
+			want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := notModified(c.req(), etag, lastModified); got != c.want {
+				t.Errorf("notModified() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+)
+
+// jsonFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1).
+//
+// FeedURL is omitted: the spec requires it to be an absolute URL, but this
+// server has no notion of its own external host/scheme (no -base-url flag
+// or similar), so it can't construct one that's guaranteed correct.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	ContentText   string   `json:"content_text,omitempty"`
+	DatePublished string   `json:"date_published,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+func jsonFeedItemFromFeedItem(fi *feedItem) jsonFeedItem {
+	return jsonFeedItem{
+		ID:            fi.guid,
+		URL:           fi.link,
+		Title:         fi.title,
+		ContentText:   fi.description,
+		DatePublished: fi.date.Format(time.RFC3339),
+		Tags:          fi.categories,
+	}
+}
+
+func (s *sheet2rss) newJSONFeed(items []*feedItem) *jsonFeed {
+	its := make([]jsonFeedItem, 0, len(items))
+	for _, fi := range items {
+		its = append(its, jsonFeedItemFromFeedItem(fi))
+	}
+
+	return &jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       s.Title,
+		HomePageURL: s.SiteURL,
+		Description: s.Desc,
+		Items:       its,
+	}
+}
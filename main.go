@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
-	"fmt"
+	"flag"
 	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"strings"
@@ -18,22 +21,74 @@ import (
 )
 
 const (
-	Title = "Federal Government 2017"
-	Desc  = "Summaries of events from the US Government."
-	URL   = "http://jlord.us/federal-gov/"
-
 	sourceFmt = "https://docs.google.com/spreadsheets/d/%s/export?format=csv"
 
-	NumEntries = 20
+	// Defaults used when running without -config, for backwards
+	// compatibility with the original single-feed deployment.
+	defaultTitle   = "Federal Government 2017"
+	defaultDesc    = "Summaries of events from the US Government."
+	defaultSiteURL = "http://jlord.us/federal-gov/"
+
+	defaultNumEntries = 20
+
+	defaultRefreshInterval = 15 * time.Minute
+
+	// refreshJitter caps how much a refresh tick is nudged earlier or
+	// later, to avoid thundering the Google Sheets endpoint.
+	refreshJitter = 30 * time.Second
 )
 
+// sheet2rss serves one spreadsheet as a feed. Title, Desc, and SiteURL
+// describe the feed itself (as opposed to url, the CSV export endpoint
+// it's fed from).
 type sheet2rss struct {
-	url string
+	Title, Desc, SiteURL string
+	mount                string
+
+	url             string
+	numEntries      int
+	refreshInterval time.Duration
+
+	// ItemHandler, if set, is called after every successful refresh with
+	// the item set from before and after the refresh, so callers can
+	// diff by GUID and notify on newly-appeared items. It runs outside
+	// mu, so a slow handler doesn't block ServeHTTP.
+	ItemHandler func(old, new []*feedItem)
+
+	mu         *sync.Mutex
+	ready      bool
+	cached     []byte
+	cachedAtom []byte
+	cachedJSON []byte
+	// digest, digestAtom, and digestJSON are per-representation content
+	// hashes: each format gets its own ETag, since they can differ (and
+	// refresh) independently of one another.
+	digest         string
+	digestAtom     string
+	digestJSON     string
+	items          []*feedItem
+	lastModified   time.Time
+	lastRefreshAt  time.Time
+	lastRefreshErr string
+
+	// upstreamETag and upstreamLastModified remember the previous
+	// response's validators so get() can make a conditional request and
+	// let Google short-circuit with a 304 when the sheet hasn't changed.
+	upstreamETag         string
+	upstreamLastModified string
+}
 
-	mu     *sync.Mutex
-	ready  bool
-	cached []byte
-	digest string
+// feedItem is the format-neutral result of parsing a sheet row. Both the
+// RSS and Atom marshalers are built from a slice of these so the CSV is
+// only ever parsed once per refresh.
+type feedItem struct {
+	rawDate     string
+	date        time.Time
+	title       string
+	link        string
+	description string
+	categories  []string
+	guid        string
 }
 
 type rss struct {
@@ -57,6 +112,14 @@ type item struct {
 	Description string   `xml:"description"`
 	PubDate     string   `xml:"pubDate,omitempty"`
 	Category    string   `xml:"category,omitempty"`
+	GUID        guid     `xml:"guid"`
+}
+
+// guid renders <guid isPermaLink="false">...</guid>. It is not a
+// permalink, just a stable identifier derived from row content.
+type guid struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
 }
 
 func parseDate(s string) time.Time {
@@ -71,41 +134,59 @@ func parseDate(s string) time.Time {
 	return time.Now().UTC()
 }
 
-func (e *item) fromRecord(headers, fields []string) {
+func (fi *feedItem) fromRecord(headers, fields []string) {
 	get := func(i int) string {
 		if i < len(fields) {
 			return fields[i]
 		}
 		return ""
 	}
-	var cats []string
 	for i, h := range headers {
 		switch h {
 		case "date":
-			d := parseDate(get(i))
-			e.PubDate = d.Format(time.RFC1123Z)
+			fi.rawDate = get(i)
+			fi.date = parseDate(fi.rawDate)
 		case "description":
-			e.Title = get(i)
+			fi.title = get(i)
 		case "article":
-			e.Link = get(i)
+			fi.link = get(i)
 		case "activity", "branch":
-			cats = append(cats, get(i))
+			fi.categories = append(fi.categories, get(i))
 		case "detail":
-			e.Description = get(i)
+			fi.description = get(i)
 		}
 	}
-	e.Category = strings.Join(cats, ",")
+	fi.guid = computeGUID(fi.rawDate, fi.title, fi.link, fi.description)
+}
+
+// computeGUID derives a stable identifier from raw row content so that
+// re-exporting or reordering the sheet doesn't change an item's GUID.
+// The synthesized PubDate (time.Now() fallback) is deliberately excluded
+// from the inputs, or the GUID would churn on every refresh.
+func computeGUID(fields ...string) string {
+	h := sha1.New()
+	io.WriteString(h, strings.Join(fields, "|"))
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-func (rs *rss) fromCSV(r io.Reader, maxRecords int) error {
+// digestOf hashes a marshaled representation for use as an ETag. Each
+// format gets its own digest: folding the format into the hash keeps a
+// client that negotiates RSS vs. Atom on the same URL from reusing one
+// representation's ETag to skip fetching the other.
+func digestOf(content []byte) string {
+	hash := sha1.Sum(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func feedItemsFromCSV(r io.Reader, maxRecords int) ([]*feedItem, error) {
 	cr := csv.NewReader(r)
 	records, err := cr.ReadAll()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(records) <= 1 {
-		return errors.New("no records")
+		return nil, errors.New("no records")
 	}
 
 	headers := records[0]
@@ -114,60 +195,194 @@ func (rs *rss) fromCSV(r io.Reader, maxRecords int) error {
 		start = 1
 	}
 
-	out := make([]*item, 0, len(records)-start)
+	out := make([]*feedItem, 0, len(records)-start)
 	for i := len(records) - 1; i > start; i-- {
-		e := new(item)
-		e.fromRecord(headers, records[i])
-		out = append(out, e)
+		fi := new(feedItem)
+		fi.fromRecord(headers, records[i])
+		out = append(out, fi)
 	}
 
-	rs.Version = "2.0"
-	rs.Channel = &channel{
-		Title:       Title,
-		Link:        URL,
-		Description: Desc,
-		Items:       out,
+	return out, nil
+}
+
+func itemFromFeedItem(fi *feedItem) *item {
+	return &item{
+		Title:       fi.title,
+		Link:        fi.link,
+		Description: fi.description,
+		PubDate:     fi.date.Format(time.RFC1123Z),
+		Category:    strings.Join(fi.categories, ","),
+		GUID:        guid{IsPermaLink: "false", Value: fi.guid},
 	}
+}
 
-	return nil
+func (s *sheet2rss) newRSS(items []*feedItem) *rss {
+	its := make([]*item, 0, len(items))
+	for _, fi := range items {
+		its = append(its, itemFromFeedItem(fi))
+	}
+
+	return &rss{
+		Version: "2.0",
+		Channel: &channel{
+			Title:       s.Title,
+			Link:        s.SiteURL,
+			Description: s.Desc,
+			Items:       its,
+		},
+	}
 }
 
-func (s *sheet2rss) get() (io.Reader, error) {
-	resp, err := http.Get(s.url)
+// get fetches the sheet, sending the previous response's validators so
+// Google can reply 304 Not Modified and spare us re-downloading and
+// re-parsing a CSV that hasn't changed. notModified is true on a 304.
+func (s *sheet2rss) get() (r io.Reader, notModified bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if s.upstreamETag != "" {
+		req.Header.Set("If-None-Match", s.upstreamETag)
+	}
+	if s.upstreamLastModified != "" {
+		req.Header.Set("If-Modified-Since", s.upstreamLastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, true, nil
+	}
+
+	s.upstreamETag = resp.Header.Get("ETag")
+	s.upstreamLastModified = resp.Header.Get("Last-Modified")
+
 	body, err := ioutil.ReadAll(resp.Body)
-	return bytes.NewBuffer(body), err
+	if err != nil {
+		return nil, false, err
+	}
+	return bytes.NewBuffer(body), false, nil
 }
 
-func (s *sheet2rss) refresh() {
-	r, err := s.get()
+// refresh fetches and reparses the sheet. On error it logs and leaves the
+// previously cached content (if any) in place rather than crashing the
+// process; only a successful refresh can flip s.ready to true.
+func (s *sheet2rss) refresh() error {
+	err := s.doRefresh()
+
+	s.mu.Lock()
+	s.lastRefreshAt = time.Now()
 	if err != nil {
-		log.Fatal(err)
+		s.lastRefreshErr = err.Error()
+	} else {
+		s.lastRefreshErr = ""
 	}
+	s.mu.Unlock()
 
-	feed := new(rss)
-	err = feed.fromCSV(r, NumEntries)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("refresh failed: %v", err)
 	}
+	return err
+}
 
-	content, err := xml.Marshal(feed)
+func (s *sheet2rss) doRefresh() error {
+	r, notModified, err := s.get()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if notModified {
+		return nil
 	}
 
-	// generate a hash of content
-	hash := sha1.Sum(content)
-	s.digest = fmt.Sprintf("% x", hash)
+	numEntries := s.numEntries
+	if numEntries <= 0 {
+		numEntries = defaultNumEntries
+	}
+	items, err := feedItemsFromCSV(r, numEntries)
+	if err != nil {
+		return err
+	}
+
+	rssContent, err := xml.Marshal(s.newRSS(items))
+	if err != nil {
+		return err
+	}
+
+	atomContent, err := xml.Marshal(s.newAtomFeed(items))
+	if err != nil {
+		return err
+	}
+
+	jsonContent, err := json.Marshal(s.newJSONFeed(items))
+	if err != nil {
+		return err
+	}
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.cached = content
+	oldItems := s.items
+	s.cached = rssContent
+	s.cachedAtom = atomContent
+	s.cachedJSON = jsonContent
+	s.digest = digestOf(rssContent)
+	s.digestAtom = digestOf(atomContent)
+	s.digestJSON = digestOf(jsonContent)
+	// Truncated to the second: Last-Modified (via http.TimeFormat) and a
+	// client's echoed If-Modified-Since both only carry second precision,
+	// so a sub-second stored value would never compare equal.
+	s.lastModified = time.Now().UTC().Truncate(time.Second)
+	s.items = items
 	s.ready = true
+	s.mu.Unlock()
+
+	// oldItems is nil only on the very first successful refresh: seed the
+	// baseline silently instead of notifying on every item already on the
+	// sheet when the process starts.
+	if s.ItemHandler != nil && oldItems != nil {
+		s.ItemHandler(oldItems, items)
+	}
+	return nil
+}
+
+// refreshLoop refreshes immediately, then on every tick of a ticker
+// (jittered so many instances don't all poll Google Sheets at once)
+// until the process exits.
+func (s *sheet2rss) refreshLoop() {
+	s.refresh()
+
+	interval := s.refreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+
+	for {
+		time.Sleep(jitter(interval))
+		s.refresh()
+	}
+}
+
+// minJitteredInterval is the floor jitter() will clamp to, so a small
+// refresh_interval (or one smaller than refreshJitter) can never produce
+// a non-positive sleep that spins the refresh loop against Google.
+const minJitteredInterval = 5 * time.Second
+
+func jitter(d time.Duration) time.Duration {
+	if refreshJitter <= 0 {
+		return d
+	}
+	offset := time.Duration(rand.Int63n(int64(2*refreshJitter))) - refreshJitter
+	jittered := d + offset
+	if jittered < minJitteredInterval {
+		return minJitteredInterval
+	}
+	return jittered
+}
+
+func acceptsAtom(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/atom+xml")
 }
 
 func (s *sheet2rss) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -176,29 +391,183 @@ func (s *sheet2rss) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var content []byte
+	pathForcesAtom := strings.HasSuffix(r.URL.Path, "/atom")
+	atom := pathForcesAtom || acceptsAtom(r)
+	if !pathForcesAtom {
+		// This URL's representation depends on Accept; tell caches so a
+		// cached RSS response is never reused for an Atom request or
+		// vice versa.
+		w.Header().Set("Vary", "Accept")
+	}
 
 	s.mu.Lock()
-	if s.ready {
-		content = s.cached
+	content, digest := s.cached, s.digest
+	if atom {
+		content, digest = s.cachedAtom, s.digestAtom
 	}
+	etag, lastModified := s.cacheValidators(digest)
 	s.mu.Unlock()
 
-	if len(content) > 0 {
-		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" ?>`))
-		w.Write(content)
+	if !s.writeCached(w, r, content, etag, lastModified) {
+		return
+	}
+
+	if atom {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
 	} else {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	}
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" ?>`))
+	w.Write(content)
+}
+
+func (s *sheet2rss) jsonFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	content := s.cachedJSON
+	etag, lastModified := s.cacheValidators(s.digestJSON)
+	s.mu.Unlock()
+
+	if !s.writeCached(w, r, content, etag, lastModified) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	w.Write(content)
+}
+
+// cacheValidators must be called with s.mu held.
+func (s *sheet2rss) cacheValidators(digest string) (etag string, lastModified time.Time) {
+	if !s.ready {
+		return "", time.Time{}
+	}
+	return `"` + digest + `"`, s.lastModified
+}
+
+// writeCached handles the not-ready/304 cases common to every feed
+// format and reports whether the caller still needs to write a body.
+func (s *sheet2rss) writeCached(w http.ResponseWriter, r *http.Request, content []byte, etag string, lastModified time.Time) bool {
+	if len(content) == 0 {
 		w.WriteHeader(http.StatusNotFound)
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
+
+// notModified honors If-None-Match and If-Modified-Since the way an
+// HTTP cache is expected to: an ETag match takes precedence, and an
+// If-Modified-Since is satisfied by any lastModified no later than it.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return inm == etag
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !lastModified.After(t)
+		}
 	}
+	return false
+}
+
+// healthzResponse is the JSON body returned by /healthz, suitable for a
+// Kubernetes liveness/readiness probe.
+type healthzResponse struct {
+	Ready          bool   `json:"ready"`
+	LastRefreshAt  string `json:"last_refresh_at,omitempty"`
+	LastRefreshErr string `json:"last_refresh_error,omitempty"`
+	Digest         string `json:"digest,omitempty"`
+}
+
+func (s *sheet2rss) health() healthzResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := healthzResponse{
+		Ready:          s.ready,
+		LastRefreshErr: s.lastRefreshErr,
+		Digest:         s.digest,
+	}
+	if !s.lastRefreshAt.IsZero() {
+		resp.LastRefreshAt = s.lastRefreshAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func (s *sheet2rss) healthz(w http.ResponseWriter, r *http.Request) {
+	resp := s.health()
+	writeHealthz(w, resp.Ready, resp)
+}
+
+func writeHealthz(w http.ResponseWriter, ready bool, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// parseRefreshInterval parses a feed's configured refresh interval,
+// falling back to defaultRefreshInterval on a blank or invalid value.
+func parseRefreshInterval(feedID, v string) time.Duration {
+	if v == "" {
+		return defaultRefreshInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("feed %q: invalid refresh interval %q, using default: %v", feedID, v, err)
+		return defaultRefreshInterval
+	}
+	return d
 }
 
 func main() {
-	handler := &sheet2rss{
-		url: fmt.Sprintf(sourceFmt, os.Getenv("SPREADSHEET_KEY")),
-		mu:  new(sync.Mutex),
+	configPath := flag.String("config", "", "path to a JSON file describing the feeds to serve")
+	flag.Parse()
+
+	var feeds []feedConfig
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("loading config %q: %v", *configPath, err)
+		}
+		feeds = cfg.Feeds
+	} else {
+		// Legacy single-feed mode, configured entirely from the
+		// environment, as before -config existed.
+		feeds = []feedConfig{{
+			ID:              "rss",
+			Title:           defaultTitle,
+			Description:     defaultDesc,
+			SiteURL:         defaultSiteURL,
+			SpreadsheetKey:  os.Getenv("SPREADSHEET_KEY"),
+			RefreshInterval: os.Getenv("REFRESH_INTERVAL"),
+			Mount:           "/rss",
+		}}
+	}
+
+	mux := http.NewServeMux()
+	var index []indexEntry
+	byID := make(map[string]*sheet2rss, len(feeds))
+	for _, fc := range feeds {
+		handler := newSheet2RSS(fc)
+		go handler.refreshLoop()
+		index = append(index, registerFeed(mux, fc, handler))
+		byID[fc.ID] = handler
 	}
-	go handler.refresh()
+	mux.HandleFunc("/", indexHandler(index))
+	mux.HandleFunc("/healthz", rootHealthzHandler(byID))
 
-	http.Handle("/rss", handler)
-	log.Fatal(http.ListenAndServe(":"+os.Getenv("PORT"), nil))
+	log.Fatal(http.ListenAndServe(":"+os.Getenv("PORT"), mux))
 }
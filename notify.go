@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// webhookItem is the JSON shape POSTed to a webhook for each new item.
+type webhookItem struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Link        string   `json:"link"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories,omitempty"`
+	Published   string   `json:"published"`
+}
+
+// newItems returns the entries in next whose GUID wasn't present in prev.
+func newItems(prev, next []*feedItem) []*feedItem {
+	seen := make(map[string]bool, len(prev))
+	for _, fi := range prev {
+		seen[fi.guid] = true
+	}
+
+	var fresh []*feedItem
+	for _, fi := range next {
+		if !seen[fi.guid] {
+			fresh = append(fresh, fi)
+		}
+	}
+	return fresh
+}
+
+// WebhookNotifier POSTs a JSON array of newly-appeared items to URL,
+// optionally signing the body with HMAC-SHA256 (GitHub-style, in the
+// X-Hub-Signature-256 header) when Secret is set.
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(old, new []*feedItem) {
+	fresh := newItems(old, new)
+	if len(fresh) == 0 {
+		return
+	}
+
+	payload := make([]webhookItem, 0, len(fresh))
+	for _, fi := range fresh {
+		payload = append(payload, webhookItem{
+			ID:          fi.guid,
+			Title:       fi.title,
+			Link:        fi.link,
+			Description: fi.description,
+			Categories:  fi.categories,
+			Published:   fi.date.Format(time.RFC3339),
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhook: marshal payload: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("webhook: build request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		req.Header.Set("X-Hub-Signature-256", "sha256="+signHMAC(n.Secret, body))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("webhook: post to %s: %v", n.URL, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("webhook: post to %s: unexpected status %s", n.URL, resp.Status)
+	}
+}
+
+func signHMAC(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SMTPNotifier emails a plain-text summary of newly-appeared items
+// through an SMTP relay.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+func (n *SMTPNotifier) Notify(old, new []*feedItem) {
+	fresh := newItems(old, new)
+	if len(fresh) == 0 {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&body, "From: %s\r\n", n.From)
+	fmt.Fprintf(&body, "Subject: %d new item(s)\r\n\r\n", len(fresh))
+	for _, fi := range fresh {
+		fmt.Fprintf(&body, "%s\n%s\n%s\n\n", fi.title, fi.link, fi.description)
+	}
+
+	if err := smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(body.String())); err != nil {
+		log.Printf("smtp: send to %v: %v", n.To, err)
+	}
+}
+
+// chainNotifiers runs each handler in turn, so a feed can be configured
+// with more than one notification channel.
+func chainNotifiers(handlers ...func(old, new []*feedItem)) func(old, new []*feedItem) {
+	return func(old, new []*feedItem) {
+		for _, h := range handlers {
+			h(old, new)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNewItems(t *testing.T) {
+	a := &feedItem{guid: "a", title: "First"}
+	b := &feedItem{guid: "b", title: "Second"}
+	c := &feedItem{guid: "c", title: "Third"}
+
+	fresh := newItems([]*feedItem{a, b}, []*feedItem{a, b, c})
+	if len(fresh) != 1 || fresh[0].guid != "c" {
+		t.Errorf("newItems() = %v, want just %v", fresh, c)
+	}
+}
+
+func TestNewItemsNoChange(t *testing.T) {
+	a := &feedItem{guid: "a"}
+	b := &feedItem{guid: "b"}
+
+	fresh := newItems([]*feedItem{a, b}, []*feedItem{a, b})
+	if len(fresh) != 0 {
+		t.Errorf("newItems() = %v, want none", fresh)
+	}
+}
+
+func TestNewItemsAllFreshWhenPrevNil(t *testing.T) {
+	a := &feedItem{guid: "a"}
+	b := &feedItem{guid: "b"}
+
+	fresh := newItems(nil, []*feedItem{a, b})
+	if len(fresh) != 2 {
+		t.Errorf("newItems(nil, ...) = %v, want both items", fresh)
+	}
+}